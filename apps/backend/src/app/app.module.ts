@@ -1,23 +1,31 @@
 import { Module } from '@nestjs/common';
+import { APP_GUARD } from '@nestjs/core';
 import { MongooseModule } from '@nestjs/mongoose';
 import { ConfigModule, ConfigService } from '@nestjs/config';
 import { AccountModule } from './account/account.module';
 import { FileModule } from './file/file.module';
+import { RateLimitGuard } from './common/rate-limit.guard';
+import { mongooseOptionsFactory } from './common/mongoose.config';
 
 @Module({
   imports: [
+    // Config is validated together with Mongo connectivity in main.ts
     ConfigModule.forRoot({
       isGlobal: true,
     }),
     MongooseModule.forRootAsync({
       imports: [ConfigModule],
-      useFactory: (configService: ConfigService) => ({
-        uri: configService.get<string>('MONGO_URI'),
-      }),
+      useFactory: mongooseOptionsFactory,
       inject: [ConfigService],
     }),
     AccountModule,
     FileModule,
   ],
+  providers: [
+    {
+      provide: APP_GUARD,
+      useClass: RateLimitGuard,
+    },
+  ],
 })
 export class AppModule {}
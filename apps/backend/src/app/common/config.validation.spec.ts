@@ -0,0 +1,89 @@
+import { collectConfigErrors } from './config.validation';
+
+describe('collectConfigErrors', () => {
+  it('should accept valid config', () => {
+    const config = {
+      MONGO_URI: 'mongodb://localhost:27017/codeed',
+      PORT: '3000',
+      MAX_REQUEST_BODY_BYTES: '1048576',
+      TRUST_PROXY_HEADERS: 'false',
+      RATE_LIMIT_PER_SECOND: '0.5',
+      RATE_LIMIT_BURST: '20',
+      MONGO_READ_PREFERENCE: 'secondaryPreferred',
+      LOG_LEVEL: 'warn',
+    };
+
+    expect(collectConfigErrors(config)).toEqual([]);
+  });
+
+  it('should accept srv uri and leave optional keys unset', () => {
+    const config = { MONGO_URI: 'mongodb+srv://cluster.example.com/codeed' };
+
+    expect(collectConfigErrors(config)).toEqual([]);
+  });
+
+  it('should require MONGO_URI', () => {
+    expect(collectConfigErrors({})).toEqual(['MONGO_URI is required']);
+  });
+
+  it('should reject bad MONGO_URI scheme', () => {
+    expect(collectConfigErrors({ MONGO_URI: 'localhost:27017' })).toEqual([
+      'MONGO_URI must start with mongodb:// or mongodb+srv://',
+    ]);
+  });
+
+  it('should reject unknown read preference', () => {
+    expect(
+      collectConfigErrors({
+        MONGO_URI: 'mongodb://localhost:27017/codeed',
+        MONGO_READ_PREFERENCE: 'secondaryOnly',
+      })
+    ).toEqual([
+      'MONGO_READ_PREFERENCE must be one of primary, primaryPreferred, ' +
+        'secondary, secondaryPreferred, nearest',
+    ]);
+  });
+
+  it('should report all invalid keys at once', () => {
+    expect(
+      collectConfigErrors({
+        MONGO_URI: 'mongodb://localhost:27017/codeed',
+        PORT: 'abc',
+        RATE_LIMIT_PER_SECOND: '-1',
+        TRUST_PROXY_HEADERS: 'yes',
+        LOG_LEVEL: 'verbose',
+      })
+    ).toEqual([
+      'LOG_LEVEL must be one of fatal, error, warn, info, debug, trace, ' +
+        'silent',
+      'PORT must be a positive integer',
+      'RATE_LIMIT_PER_SECOND must be a positive number',
+      'TRUST_PROXY_HEADERS must be false, a hop count, ' +
+        'or a comma-separated list of proxy IPs/CIDRs',
+    ]);
+  });
+
+  it.each(['false', '1', '10.0.0.1', '10.0.0.0/8, ::1', 'loopback'])(
+    'should accept TRUST_PROXY_HEADERS=%p',
+    (value) => {
+      expect(
+        collectConfigErrors({
+          MONGO_URI: 'mongodb://localhost:27017/codeed',
+          TRUST_PROXY_HEADERS: value,
+        })
+      ).toEqual([]);
+    }
+  );
+
+  it.each(['true', '0', '10.0.0.0/33', 'proxy.local'])(
+    'should reject TRUST_PROXY_HEADERS=%p',
+    (value) => {
+      expect(
+        collectConfigErrors({
+          MONGO_URI: 'mongodb://localhost:27017/codeed',
+          TRUST_PROXY_HEADERS: value,
+        })
+      ).toEqual([expect.stringMatching(/^TRUST_PROXY_HEADERS must be false/)]);
+    }
+  );
+});
@@ -0,0 +1,99 @@
+import { ReadPreferenceMode } from 'mongodb';
+import { isIP } from 'net';
+
+const MONGO_URI_PATTERN = /^mongodb(\+srv)?:\/\//;
+const INTEGER_KEYS = [
+  'PORT',
+  'MAX_REQUEST_BODY_BYTES',
+  'MAX_UPLOAD_FILES',
+  'RATE_LIMIT_BURST',
+  'RATE_LIMIT_MAX_CLIENTS',
+  'WRITE_RETRY_ATTEMPTS',
+  'WRITE_RETRY_BACKOFF_MS',
+];
+const NUMBER_KEYS = ['RATE_LIMIT_PER_SECOND'];
+// Named ranges understood by Fastify's trustProxy (proxy-addr)
+const PROXY_RANGE_NAMES = ['loopback', 'linklocal', 'uniquelocal'];
+const LOG_LEVELS = [
+  'fatal',
+  'error',
+  'warn',
+  'info',
+  'debug',
+  'trace',
+  'silent',
+];
+
+const isSet = (value: unknown) => value !== undefined && value !== '';
+
+const isProxyEntry = (entry: string) => {
+  if (PROXY_RANGE_NAMES.includes(entry)) {
+    return true;
+  }
+  const [address, prefix, ...rest] = entry.split('/');
+  const version = isIP(address);
+  if (version === 0 || rest.length > 0) {
+    return false;
+  }
+  return (
+    prefix === undefined ||
+    (/^\d+$/.test(prefix) && Number(prefix) <= (version === 4 ? 32 : 128))
+  );
+};
+
+const isTrustProxy = (value: string) =>
+  value === 'false' ||
+  /^[1-9]\d*$/.test(value) ||
+  value.split(',').every((entry) => isProxyEntry(entry.trim()));
+
+/**
+ * Checks environment configuration so the app fails fast on startup with
+ * every problem listed instead of breaking on first use.
+ * @param config - Raw environment variables
+ * @returns Messages for invalid or missing variables, empty if valid
+ */
+export function collectConfigErrors(config: Record<string, unknown>): string[] {
+  const errors: string[] = [];
+
+  const mongoUri = config.MONGO_URI;
+  if (!isSet(mongoUri)) {
+    errors.push('MONGO_URI is required');
+  } else if (!MONGO_URI_PATTERN.test(String(mongoUri))) {
+    errors.push('MONGO_URI must start with mongodb:// or mongodb+srv://');
+  }
+
+  const readPreference = config.MONGO_READ_PREFERENCE;
+  const readPreferences: unknown[] = Object.values(ReadPreferenceMode);
+  if (isSet(readPreference) && !readPreferences.includes(readPreference)) {
+    errors.push(
+      `MONGO_READ_PREFERENCE must be one of ${readPreferences.join(', ')}`
+    );
+  }
+
+  const logLevel = config.LOG_LEVEL;
+  if (isSet(logLevel) && !LOG_LEVELS.includes(String(logLevel))) {
+    errors.push(`LOG_LEVEL must be one of ${LOG_LEVELS.join(', ')}`);
+  }
+
+  for (const key of INTEGER_KEYS) {
+    if (isSet(config[key]) && !/^[1-9]\d*$/.test(String(config[key]))) {
+      errors.push(`${key} must be a positive integer`);
+    }
+  }
+
+  for (const key of NUMBER_KEYS) {
+    if (isSet(config[key]) && !(Number(config[key]) > 0)) {
+      errors.push(`${key} must be a positive number`);
+    }
+  }
+
+  const trustProxy = config.TRUST_PROXY_HEADERS;
+  if (isSet(trustProxy) && !isTrustProxy(String(trustProxy))) {
+    errors.push(
+      'TRUST_PROXY_HEADERS must be false, a hop count, ' +
+        'or a comma-separated list of proxy IPs/CIDRs'
+    );
+  }
+
+  return errors;
+}
@@ -0,0 +1,80 @@
+import { HttpStatus } from '@nestjs/common';
+import * as fileErrors from '../file/file.errors';
+import {
+  DomainError,
+  DomainErrorClass,
+  ErrorCode,
+  registeredErrorCodes,
+  toHttpException,
+} from './domain-error';
+import * as objectId from './object-id';
+import { InvalidObjectIdError } from './object-id';
+import * as rateLimit from './rate-limit.guard';
+import * as storage from './storage';
+
+// Every module that defines DomainError subclasses
+const errorModules: Record<string, unknown>[] = [
+  fileErrors,
+  objectId,
+  rateLimit,
+  storage,
+];
+
+const isDomainErrorClass = (value: unknown): value is DomainErrorClass =>
+  typeof value === 'function' && value.prototype instanceof DomainError;
+
+describe('DomainError', () => {
+  const errorClasses = errorModules
+    .flatMap((module) => Object.values(module))
+    .filter(isDomainErrorClass);
+
+  it('should register a code for every domain error', () => {
+    const registered = [...registeredErrorCodes().values()];
+
+    expect(errorClasses.length).toBeGreaterThan(0);
+    for (const errorClass of errorClasses) {
+      expect(registered).toContain(errorClass);
+    }
+  });
+
+  it('should use snake_case codes', () => {
+    for (const code of registeredErrorCodes().keys()) {
+      expect(code).toMatch(/^[a-z]+(_[a-z]+)*$/);
+    }
+  });
+
+  it('should reject a code that is already registered', () => {
+    expect(() => {
+      @ErrorCode('invalid_object_id')
+      class DuplicateError extends DomainError {}
+      return DuplicateError;
+    }).toThrow('Error code invalid_object_id is already used by');
+  });
+
+  it('should reject a domain error without a code', () => {
+    class UncodedError extends DomainError {}
+
+    expect(() => new UncodedError()).toThrow('UncodedError has no @ErrorCode');
+  });
+
+  it('should read code from the registry', () => {
+    expect(new InvalidObjectIdError('bad_id').code).toBe('invalid_object_id');
+  });
+
+  it('should expose code in http exception body', () => {
+    const exception = toHttpException(
+      new InvalidObjectIdError('bad_id'),
+      'Invalid file ID',
+      HttpStatus.BAD_REQUEST
+    );
+
+    expect(exception.getStatus()).toBe(HttpStatus.BAD_REQUEST);
+    expect(exception.message).toBe('Invalid file ID');
+    expect(exception.getResponse()).toEqual({
+      statusCode: HttpStatus.BAD_REQUEST,
+      error: 'Bad Request',
+      message: 'Invalid file ID',
+      code: 'invalid_object_id',
+    });
+  });
+});
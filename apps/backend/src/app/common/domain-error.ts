@@ -0,0 +1,78 @@
+import { HttpException, HttpStatus } from '@nestjs/common';
+import { STATUS_CODES } from 'http';
+
+export type DomainErrorClass = new (...args: never[]) => DomainError;
+
+const classesByCode = new Map<string, DomainErrorClass>();
+const codesByClass = new Map<DomainErrorClass, string>();
+
+/**
+ * Registers the stable machine-readable code of a DomainError subclass.
+ * Codes must be unique; registering one twice fails when the class is
+ * defined, so a clash cannot reach clients.
+ * @param code - snake_case error code
+ * @throws Error if the code is already registered by another class
+ */
+export function ErrorCode(code: string) {
+  return (target: DomainErrorClass): void => {
+    const existing = classesByCode.get(code);
+    if (existing) {
+      throw new Error(`Error code ${code} is already used by ${existing.name}`);
+    }
+    classesByCode.set(code, target);
+    codesByClass.set(target, code);
+  };
+}
+
+/**
+ * Lists every registered error code with the class that owns it.
+ */
+export function registeredErrorCodes(): ReadonlyMap<string, DomainErrorClass> {
+  return classesByCode;
+}
+
+/**
+ * DomainError is the base for errors raised by services. Each subclass
+ * declares its code with the ErrorCode decorator; the code is exposed in
+ * HTTP error responses.
+ */
+export abstract class DomainError extends Error {
+  /**
+   * @throws Error if the concrete class has no ErrorCode, so a missing
+   * decorator fails where the error is raised instead of yielding a
+   * response without a code
+   */
+  constructor(message?: string) {
+    super(message);
+    if (!codesByClass.has(new.target as DomainErrorClass)) {
+      throw new Error(`${new.target.name} has no @ErrorCode`);
+    }
+  }
+
+  get code(): string {
+    return codesByClass.get(this.constructor as DomainErrorClass);
+  }
+}
+
+/**
+ * Builds an HttpException with the standard Nest error body plus the
+ * domain error code: { statusCode, error, message, code }.
+ * @param error - Domain error to expose
+ * @param message - Human-readable message for the client
+ * @param status - HTTP status to respond with
+ */
+export function toHttpException(
+  error: DomainError,
+  message: string,
+  status: HttpStatus
+): HttpException {
+  return new HttpException(
+    {
+      statusCode: status,
+      error: STATUS_CODES[status],
+      message,
+      code: error.code,
+    },
+    status
+  );
+}
@@ -0,0 +1,32 @@
+import { ConfigService } from '@nestjs/config';
+import { mongooseOptionsFactory } from './mongoose.config';
+
+describe('mongooseOptionsFactory', () => {
+  const configWith = (values: Record<string, string>) =>
+    ({ get: (key: string) => values[key] } as unknown as ConfigService);
+
+  it('should build options with read preference from config', () => {
+    const options = mongooseOptionsFactory(
+      configWith({
+        MONGO_URI: 'mongodb://localhost:27017/codeed',
+        MONGO_READ_PREFERENCE: 'secondaryPreferred',
+      })
+    );
+
+    expect(options).toEqual({
+      uri: 'mongodb://localhost:27017/codeed',
+      serverSelectionTimeoutMS: 5000,
+      retryAttempts: 3,
+      retryDelay: 1000,
+      readPreference: 'secondaryPreferred',
+    });
+  });
+
+  it('should omit read preference when not configured', () => {
+    const options = mongooseOptionsFactory(
+      configWith({ MONGO_URI: 'mongodb://localhost:27017/codeed' })
+    );
+
+    expect(options).not.toHaveProperty('readPreference');
+  });
+});
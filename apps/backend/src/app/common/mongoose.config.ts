@@ -0,0 +1,27 @@
+import { ConfigService } from '@nestjs/config';
+import { MongooseModuleFactoryOptions } from '@nestjs/mongoose';
+import { ReadPreferenceMode } from 'mongodb';
+
+/**
+ * Builds Mongoose connection options from configuration.
+ * MONGO_READ_PREFERENCE is optional; when unset the driver default
+ * (or the one given in MONGO_URI) applies.
+ * @param configService - Application config
+ */
+export function mongooseOptionsFactory(
+  configService: ConfigService
+): MongooseModuleFactoryOptions {
+  const readPreference = configService.get<ReadPreferenceMode>(
+    'MONGO_READ_PREFERENCE'
+  );
+
+  return {
+    uri: configService.get<string>('MONGO_URI'),
+    // Fail startup quickly when Mongo is unreachable. Reachability is
+    // checked before bootstrap, so only brief outages are retried here.
+    serverSelectionTimeoutMS: 5000,
+    retryAttempts: 3,
+    retryDelay: 1000,
+    ...(readPreference ? { readPreference } : {}),
+  };
+}
@@ -0,0 +1,30 @@
+import { ObjectId } from 'mongodb';
+import { InvalidObjectIdError, parseObjectId } from './object-id';
+
+describe('parseObjectId', () => {
+  it('should parse valid hex string', () => {
+    const id = parseObjectId('507f191e810c19729de860ea');
+
+    expect(id).toBeInstanceOf(ObjectId);
+    expect(id.toHexString()).toBe('507f191e810c19729de860ea');
+  });
+
+  it('should accept uppercase hex', () => {
+    const id = parseObjectId('507F191E810C19729DE860EA');
+
+    expect(id.toHexString()).toBe('507f191e810c19729de860ea');
+  });
+
+  it.each([
+    '',
+    'bad_id',
+    '507f191e810c19729de860e',
+    'zzzzzzzzzzzzzzzzzzzzzzzz',
+  ])('should throw InvalidObjectIdError for %p', (value) => {
+    expect(() => parseObjectId(value)).toThrow(InvalidObjectIdError);
+  });
+
+  it('should reject 12-byte non-hex strings', () => {
+    expect(() => parseObjectId('abcdefghijkl')).toThrow(InvalidObjectIdError);
+  });
+});
@@ -0,0 +1,29 @@
+import { ObjectId } from 'mongodb';
+import { DomainError, ErrorCode } from './domain-error';
+
+const OBJECT_ID_PATTERN = /^[0-9a-fA-F]{24}$/;
+
+/**
+ * InvalidObjectIdError is thrown when a string is not a valid 24-character
+ * hex ObjectId.
+ */
+@ErrorCode('invalid_object_id')
+export class InvalidObjectIdError extends DomainError {
+  constructor(readonly value: string) {
+    super(`Invalid ObjectId: ${value}`);
+    this.name = InvalidObjectIdError.name;
+  }
+}
+
+/**
+ * Parses a hex string into an ObjectId.
+ * @param id - ObjectId as 24-character hex string
+ * @returns Parsed ObjectId
+ * @throws InvalidObjectIdError if the string is not a valid hex ObjectId
+ */
+export function parseObjectId(id: string): ObjectId {
+  if (typeof id !== 'string' || !OBJECT_ID_PATTERN.test(id)) {
+    throw new InvalidObjectIdError(id);
+  }
+  return new ObjectId(id);
+}
@@ -0,0 +1,138 @@
+import { ExecutionContext, HttpException, Logger } from '@nestjs/common';
+import { ConfigService } from '@nestjs/config';
+import { Test, TestingModule } from '@nestjs/testing';
+import { RateLimitGuard } from './rate-limit.guard';
+
+describe('RateLimitGuard', () => {
+  let guard: RateLimitGuard;
+  let reply: { header: jest.Mock };
+  let now: jest.SpyInstance<number>;
+
+  const config: Record<string, string> = {
+    RATE_LIMIT_PER_SECOND: '1',
+    RATE_LIMIT_BURST: '2',
+    RATE_LIMIT_MAX_CLIENTS: '2',
+  };
+
+  const rejectionFor = (ip: string) => {
+    try {
+      guard.canActivate(contextFor(ip));
+    } catch (error) {
+      return error;
+    }
+    throw new Error(`Request from ${ip} was not rejected`);
+  };
+
+  const contextFor = (ip: string) =>
+    ({
+      switchToHttp: () => ({
+        getRequest: () => ({ ip }),
+        getResponse: () => reply,
+      }),
+    } as unknown as ExecutionContext);
+
+  beforeEach(async () => {
+    now = jest.spyOn(Date, 'now').mockReturnValue(1717200000000);
+    reply = { header: jest.fn() };
+
+    const module: TestingModule = await Test.createTestingModule({
+      providers: [
+        RateLimitGuard,
+        {
+          provide: ConfigService,
+          useValue: { get: (key: string) => config[key] },
+        },
+      ],
+    }).compile();
+
+    guard = module.get<RateLimitGuard>(RateLimitGuard);
+  });
+
+  afterEach(() => {
+    guard.onModuleDestroy();
+    now.mockRestore();
+    jest.restoreAllMocks();
+  });
+
+  it('should allow requests within burst', () => {
+    expect(guard.canActivate(contextFor('1.1.1.1'))).toBe(true);
+    expect(guard.canActivate(contextFor('1.1.1.1'))).toBe(true);
+  });
+
+  it('should return 429 with Retry-After when limit is exceeded', () => {
+    guard.canActivate(contextFor('1.1.1.1'));
+    guard.canActivate(contextFor('1.1.1.1'));
+
+    const error = rejectionFor('1.1.1.1');
+
+    expect(error).toBeInstanceOf(HttpException);
+    expect(error.getStatus()).toBe(429);
+    expect(error.message).toBe('Too many requests');
+    expect(error.getResponse()).toMatchObject({ code: 'rate_limited' });
+    expect(reply.header).toHaveBeenCalledWith('Retry-After', '1');
+  });
+
+  it('should warn once per limited period', () => {
+    const warn = jest.spyOn(Logger.prototype, 'warn').mockImplementation();
+    guard.canActivate(contextFor('1.1.1.1'));
+    guard.canActivate(contextFor('1.1.1.1'));
+
+    rejectionFor('1.1.1.1');
+    rejectionFor('1.1.1.1');
+    expect(warn).toHaveBeenCalledTimes(1);
+
+    now.mockReturnValue(1717200001000);
+    guard.canActivate(contextFor('1.1.1.1'));
+    rejectionFor('1.1.1.1');
+    expect(warn).toHaveBeenCalledTimes(2);
+  });
+
+  it('should evict least recently seen client when full', () => {
+    guard.canActivate(contextFor('1.1.1.1'));
+    guard.canActivate(contextFor('1.1.1.1'));
+    guard.canActivate(contextFor('2.2.2.2'));
+    // Touching 1.1.1.1 makes 2.2.2.2 the eviction candidate
+    rejectionFor('1.1.1.1');
+
+    guard.canActivate(contextFor('3.3.3.3'));
+
+    expect(rejectionFor('1.1.1.1').getStatus()).toBe(429);
+  });
+
+  it('should start evicted client with a full bucket', () => {
+    guard.canActivate(contextFor('1.1.1.1'));
+    guard.canActivate(contextFor('1.1.1.1'));
+    guard.canActivate(contextFor('2.2.2.2'));
+    guard.canActivate(contextFor('3.3.3.3'));
+
+    expect(guard.canActivate(contextFor('1.1.1.1'))).toBe(true);
+  });
+
+  it('should track clients separately', () => {
+    guard.canActivate(contextFor('1.1.1.1'));
+    guard.canActivate(contextFor('1.1.1.1'));
+
+    expect(guard.canActivate(contextFor('2.2.2.2'))).toBe(true);
+  });
+
+  it('should refill bucket over time', () => {
+    guard.canActivate(contextFor('1.1.1.1'));
+    guard.canActivate(contextFor('1.1.1.1'));
+    rejectionFor('1.1.1.1');
+
+    now.mockReturnValue(1717200001000);
+
+    expect(guard.canActivate(contextFor('1.1.1.1'))).toBe(true);
+  });
+
+  it('should drop idle buckets on cleanup', () => {
+    guard.canActivate(contextFor('1.1.1.1'));
+    guard.canActivate(contextFor('1.1.1.1'));
+
+    guard.cleanup(1717200002000);
+
+    // A forgotten client starts again with a full bucket
+    expect(guard.canActivate(contextFor('1.1.1.1'))).toBe(true);
+    expect(guard.canActivate(contextFor('1.1.1.1'))).toBe(true);
+  });
+});
@@ -0,0 +1,122 @@
+import {
+  CanActivate,
+  ExecutionContext,
+  HttpStatus,
+  Injectable,
+  Logger,
+  OnModuleDestroy,
+} from '@nestjs/common';
+import { ConfigService } from '@nestjs/config';
+import { FastifyReply, FastifyRequest } from 'fastify';
+import { DomainError, ErrorCode, toHttpException } from './domain-error';
+
+interface Bucket {
+  tokens: number;
+  updatedAt: number;
+  /** Set once a request is rejected, cleared by the next allowed one */
+  limited: boolean;
+}
+
+const CLEANUP_INTERVAL_MS = 60_000;
+
+/**
+ * RateLimitExceededError is raised when a client has no tokens left.
+ */
+@ErrorCode('rate_limited')
+export class RateLimitExceededError extends DomainError {
+  constructor(readonly clientIp: string) {
+    super(`Rate limit exceeded for ${clientIp}`);
+    this.name = RateLimitExceededError.name;
+  }
+}
+
+/**
+ * RateLimitGuard applies a per-IP token bucket to every request.
+ * Rate (tokens per second) and burst are read from RATE_LIMIT_PER_SECOND
+ * and RATE_LIMIT_BURST. Idle buckets are dropped periodically, and at most
+ * RATE_LIMIT_MAX_CLIENTS buckets are kept, evicting the least recently
+ * seen client first.
+ */
+@Injectable()
+export class RateLimitGuard implements CanActivate, OnModuleDestroy {
+  private readonly logger = new Logger(RateLimitGuard.name);
+  private readonly buckets = new Map<string, Bucket>();
+  private readonly rate: number;
+  private readonly burst: number;
+  private readonly maxClients: number;
+  private readonly cleanupTimer: NodeJS.Timeout;
+
+  constructor(configService: ConfigService) {
+    this.rate = Number(configService.get('RATE_LIMIT_PER_SECOND')) || 10;
+    this.burst = Number(configService.get('RATE_LIMIT_BURST')) || 20;
+    this.maxClients =
+      Number(configService.get('RATE_LIMIT_MAX_CLIENTS')) || 10_000;
+    this.cleanupTimer = setInterval(() => this.cleanup(), CLEANUP_INTERVAL_MS);
+    this.cleanupTimer.unref();
+  }
+
+  canActivate(context: ExecutionContext): boolean {
+    const http = context.switchToHttp();
+    const request = http.getRequest<FastifyRequest>();
+    const reply = http.getResponse<FastifyReply>();
+
+    const bucket = this.refill(request.ip, Date.now());
+    if (bucket.tokens >= 1) {
+      bucket.tokens -= 1;
+      bucket.limited = false;
+      return true;
+    }
+
+    const retryAfter = Math.ceil((1 - bucket.tokens) / this.rate);
+    // Log once per limited period rather than on every rejected request
+    if (!bucket.limited) {
+      bucket.limited = true;
+      this.logger.warn(`Rate limit exceeded for ${request.ip}`);
+    }
+    reply.header('Retry-After', String(retryAfter));
+    throw toHttpException(
+      new RateLimitExceededError(request.ip),
+      'Too many requests',
+      HttpStatus.TOO_MANY_REQUESTS
+    );
+  }
+
+  /**
+   * Removes buckets that have refilled completely, i.e. clients idle long
+   * enough that forgetting them changes nothing.
+   * @param now - Current time in milliseconds
+   */
+  cleanup(now = Date.now()): void {
+    for (const [key, bucket] of this.buckets) {
+      const elapsed = (now - bucket.updatedAt) / 1000;
+      if (bucket.tokens + elapsed * this.rate >= this.burst) {
+        this.buckets.delete(key);
+      }
+    }
+  }
+
+  onModuleDestroy(): void {
+    clearInterval(this.cleanupTimer);
+  }
+
+  private refill(key: string, now: number): Bucket {
+    const bucket = this.buckets.get(key);
+    if (!bucket) {
+      if (this.buckets.size >= this.maxClients) {
+        // Map iterates in insertion order, so the first key is the LRU one
+        this.buckets.delete(this.buckets.keys().next().value);
+      }
+      const created = { tokens: this.burst, updatedAt: now, limited: false };
+      this.buckets.set(key, created);
+      return created;
+    }
+
+    // Re-insert to mark the client as most recently seen
+    this.buckets.delete(key);
+    this.buckets.set(key, bucket);
+    const elapsed = (now - bucket.updatedAt) / 1000;
+    bucket.tokens = Math.min(this.burst, bucket.tokens + elapsed * this.rate);
+    bucket.updatedAt = now;
+    return bucket;
+  }
+}
@@ -0,0 +1,54 @@
+import { Logger } from '@nestjs/common';
+import { FastifyBaseLogger } from 'fastify';
+import { RequestLogger, runWithRequestLog } from './request-context';
+
+describe('RequestLogger', () => {
+  const logger = new RequestLogger('TestService');
+  const requestLog = {
+    info: jest.fn(),
+    warn: jest.fn(),
+    error: jest.fn(),
+  } as unknown as FastifyBaseLogger;
+
+  afterEach(() => {
+    jest.restoreAllMocks();
+    jest.clearAllMocks();
+  });
+
+  it('should fall back to Nest logger outside a request', () => {
+    const log = jest.spyOn(Logger.prototype, 'log').mockImplementation();
+
+    logger.log('starting');
+
+    expect(log).toHaveBeenCalledWith('starting');
+    expect(requestLog.info).not.toHaveBeenCalled();
+  });
+
+  it('should write through the request logger inside a request', async () => {
+    await runWithRequestLog(requestLog, async () => {
+      await Promise.resolve();
+      logger.log('handling');
+      logger.warn('careful');
+    });
+
+    expect(requestLog.info).toHaveBeenCalledWith(
+      { context: 'TestService' },
+      'handling'
+    );
+    expect(requestLog.warn).toHaveBeenCalledWith(
+      { context: 'TestService' },
+      'careful'
+    );
+  });
+
+  it('should pass error to the request logger', () => {
+    const error = new Error('boom');
+
+    runWithRequestLog(requestLog, () => logger.error('failed', error));
+
+    expect(requestLog.error).toHaveBeenCalledWith(
+      { context: 'TestService', err: error },
+      'failed'
+    );
+  });
+});
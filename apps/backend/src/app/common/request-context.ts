@@ -0,0 +1,76 @@
+/* eslint-disable @typescript-eslint/no-explicit-any */
+import { Logger } from '@nestjs/common';
+import { AsyncLocalStorage, AsyncResource } from 'async_hooks';
+import { FastifyBaseLogger, FastifyInstance, FastifyRequest } from 'fastify';
+
+const requestLogs = new AsyncLocalStorage<FastifyBaseLogger>();
+const requestScopes = new WeakMap<FastifyRequest, AsyncResource>();
+
+/**
+ * Runs a function with the given logger as the current request logger.
+ * @param log - Request-bound Fastify logger
+ * @param fn - Function to run
+ */
+export function runWithRequestLog<T>(log: FastifyBaseLogger, fn: () => T): T {
+  return requestLogs.run(log, fn);
+}
+
+/**
+ * Makes each request's Fastify logger (bound to its reqId) available to
+ * RequestLogger for everything that runs while handling the request.
+ * @param fastify - Fastify instance behind the Nest application
+ */
+export function registerRequestContext(fastify: FastifyInstance): void {
+  fastify.addHook('onRequest', (request, _reply, done) => {
+    runWithRequestLog(request.log, () => {
+      requestScopes.set(request, new AsyncResource('codeed-request'));
+      done();
+    });
+  });
+  // Body parsing continues from socket events, outside the request's
+  // async context, so the context is re-entered before the handler runs
+  fastify.addHook('preValidation', (request, _reply, done) => {
+    const scope = requestScopes.get(request);
+    if (scope) {
+      scope.runInAsyncScope(done, request.raw);
+    } else {
+      done();
+    }
+  });
+}
+
+/**
+ * RequestLogger is a drop-in replacement for Nest's Logger. While handling
+ * a request it writes through the request's Fastify logger, so service log
+ * lines carry the reqId; elsewhere (startup, timers) it falls back to the
+ * regular Nest logger.
+ */
+export class RequestLogger extends Logger {
+  log(message: any, ...optionalParams: any[]): void {
+    const log = requestLogs.getStore();
+    if (log) {
+      log.info({ context: this.context }, message);
+    } else {
+      super.log(message, ...optionalParams);
+    }
+  }
+
+  warn(message: any, ...optionalParams: any[]): void {
+    const log = requestLogs.getStore();
+    if (log) {
+      log.warn({ context: this.context }, message);
+    } else {
+      super.warn(message, ...optionalParams);
+    }
+  }
+
+  error(message: any, ...optionalParams: any[]): void {
+    const log = requestLogs.getStore();
+    if (log) {
+      const [err] = optionalParams;
+      log.error({ context: this.context, err }, message);
+    } else {
+      super.error(message, ...optionalParams);
+    }
+  }
+}
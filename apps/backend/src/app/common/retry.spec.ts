@@ -0,0 +1,69 @@
+import { MongoError, MongoErrorLabel, MongoRuntimeError } from 'mongodb';
+import { isRetryableWriteError, withRetry } from './retry';
+
+const retryableError = () => {
+  const error = new MongoError('not primary');
+  error.addErrorLabel(MongoErrorLabel.RetryableWriteError);
+  return error;
+};
+
+describe('withRetry', () => {
+  const options = { attempts: 3, backoffMs: 1 };
+
+  afterEach(() => {
+    jest.useRealTimers();
+  });
+
+  it('should detect retryable write errors by label', () => {
+    expect(isRetryableWriteError(retryableError())).toBe(true);
+    expect(isRetryableWriteError(new MongoError('duplicate key'))).toBe(false);
+    expect(isRetryableWriteError(new Error('boom'))).toBe(false);
+  });
+
+  it('should retry until operation succeeds', async () => {
+    const operation = jest
+      .fn()
+      .mockRejectedValueOnce(retryableError())
+      .mockRejectedValueOnce(retryableError())
+      .mockResolvedValue('ok');
+
+    await expect(withRetry(operation, options)).resolves.toBe('ok');
+    expect(operation).toHaveBeenCalledTimes(3);
+  });
+
+  it('should give up after configured attempts', async () => {
+    const error = retryableError();
+    const operation = jest.fn().mockRejectedValue(error);
+
+    await expect(withRetry(operation, options)).rejects.toBe(error);
+    expect(operation).toHaveBeenCalledTimes(3);
+  });
+
+  it('should not retry non-retryable errors', async () => {
+    const error = new MongoRuntimeError('File not found');
+    const operation = jest.fn().mockRejectedValue(error);
+
+    await expect(withRetry(operation, options)).rejects.toBe(error);
+    expect(operation).toHaveBeenCalledTimes(1);
+  });
+
+  it('should back off exponentially between attempts', async () => {
+    jest.useFakeTimers();
+    const operation = jest
+      .fn()
+      .mockRejectedValueOnce(retryableError())
+      .mockRejectedValueOnce(retryableError())
+      .mockResolvedValue('ok');
+
+    const result = withRetry(operation, { attempts: 3, backoffMs: 100 });
+
+    await jest.advanceTimersByTimeAsync(99);
+    expect(operation).toHaveBeenCalledTimes(1);
+    await jest.advanceTimersByTimeAsync(1);
+    expect(operation).toHaveBeenCalledTimes(2);
+    await jest.advanceTimersByTimeAsync(199);
+    expect(operation).toHaveBeenCalledTimes(2);
+    await jest.advanceTimersByTimeAsync(1);
+    await expect(result).resolves.toBe('ok');
+  });
+});
@@ -0,0 +1,47 @@
+import { MongoError, MongoErrorLabel } from 'mongodb';
+
+/**
+ * Retry policy for write operations.
+ */
+export interface RetryOptions {
+  /** Total number of attempts, including the first one */
+  attempts: number;
+  /** Delay before the first retry in milliseconds, doubled on each retry */
+  backoffMs: number;
+}
+
+/**
+ * Checks whether the driver labelled an error as safe to retry
+ * (network blips, primary stepdown).
+ * @param error - Error raised by the MongoDB driver
+ */
+export function isRetryableWriteError(error: unknown): boolean {
+  return (
+    error instanceof MongoError &&
+    error.hasErrorLabel(MongoErrorLabel.RetryableWriteError)
+  );
+}
+
+/**
+ * Runs a write operation, retrying it with exponential backoff while it
+ * fails with a retryable error. Other errors are thrown immediately.
+ * @param operation - Write to perform
+ * @param options - Retry policy
+ * @returns Result of the first successful attempt
+ */
+export async function withRetry<T>(
+  operation: () => Promise<T>,
+  options: RetryOptions
+): Promise<T> {
+  for (let attempt = 1; ; attempt++) {
+    try {
+      return await operation();
+    } catch (error) {
+      if (attempt >= options.attempts || !isRetryableWriteError(error)) {
+        throw error;
+      }
+      const delay = options.backoffMs * 2 ** (attempt - 1);
+      await new Promise((resolve) => setTimeout(resolve, delay));
+    }
+  }
+}
@@ -0,0 +1,342 @@
+import {
+  Body,
+  Controller,
+  Get,
+  Injectable,
+  Post,
+  Req,
+} from '@nestjs/common';
+import {
+  FastifyAdapter,
+  NestFastifyApplication,
+} from '@nestjs/platform-fastify';
+import { Test } from '@nestjs/testing';
+import { FastifyRequest, FastifyServerOptions } from 'fastify';
+import { ObjectId } from 'mongodb';
+import { pipeline, Writable } from 'stream';
+import { FileController } from '../file/file.controller';
+import { FileService } from '../file/file.service';
+import { RequestLogger } from './request-context';
+import {
+  configureServer,
+  fastifyOptions,
+  parseTrustProxy,
+  serverOptionsFromEnv,
+} from './server.config';
+
+@Injectable()
+class EchoService {
+  private readonly logger = new RequestLogger(EchoService.name);
+
+  async echo(body: unknown) {
+    await new Promise((resolve) => setImmediate(resolve));
+    this.logger.log('Echoing body');
+    return body;
+  }
+}
+
+@Controller('echo')
+class EchoController {
+  constructor(private readonly echoService: EchoService) {}
+
+  @Post('logged')
+  logged(@Body() body: unknown) {
+    return this.echoService.echo(body);
+  }
+
+  @Post()
+  echo(@Body() body: unknown) {
+    return body;
+  }
+
+  @Get('ip')
+  ip(@Req() req: FastifyRequest) {
+    return { ip: req.ip };
+  }
+}
+
+const BOUNDARY = 'codeed-test-boundary';
+
+function multipartUpload(...contents: string[]) {
+  const parts = contents.map(
+    (content, index) =>
+      `--${BOUNDARY}\r\n` +
+      `Content-Disposition: form-data; name="file${index}"; ` +
+      `filename="test${index}.txt"\r\n` +
+      'Content-Type: text/plain\r\n\r\n' +
+      `${content}\r\n`
+  );
+  return {
+    method: 'POST' as const,
+    url: '/files/upload',
+    headers: { 'content-type': `multipart/form-data; boundary=${BOUNDARY}` },
+    payload: parts.join('') + `--${BOUNDARY}--\r\n`,
+  };
+}
+
+describe('server config', () => {
+  let app: NestFastifyApplication;
+
+  const mockFileService = {
+    // Drains the stream like GridFS would
+    upload: jest.fn(
+      (_filename: string, stream: NodeJS.ReadableStream) =>
+        new Promise<ObjectId>((resolve, reject) => {
+          const sink = new Writable({
+            write(_chunk, _encoding, callback) {
+              callback();
+            },
+          });
+          pipeline(stream, sink, (err) =>
+            err ? reject(err) : resolve(new ObjectId())
+          );
+        })
+    ),
+    streamFile: jest.fn(),
+    deleteFile: jest.fn().mockResolvedValue(undefined),
+  };
+
+  const createApp = async (
+    env: NodeJS.ProcessEnv,
+    logger: FastifyServerOptions['logger'] = false
+  ) => {
+    const options = serverOptionsFromEnv(env);
+    const module = await Test.createTestingModule({
+      controllers: [EchoController, FileController],
+      providers: [
+        EchoService,
+        { provide: FileService, useValue: mockFileService },
+      ],
+    }).compile();
+
+    const instance = module.createNestApplication<NestFastifyApplication>(
+      new FastifyAdapter({ ...fastifyOptions(options), logger })
+    );
+    await configureServer(instance, options);
+    await instance.init();
+    await instance.getHttpAdapter().getInstance().ready();
+    return instance;
+  };
+
+  afterEach(async () => {
+    await app?.close();
+    jest.clearAllMocks();
+  });
+
+  describe('body limit', () => {
+    beforeEach(async () => {
+      app = await createApp({ MAX_REQUEST_BODY_BYTES: '1024' });
+    });
+
+    it('should accept body within limit', async () => {
+      const response = await app.inject({
+        method: 'POST',
+        url: '/echo',
+        payload: { text: 'hello' },
+      });
+
+      expect(response.statusCode).toBe(201);
+    });
+
+    it('should reject oversized body with 413', async () => {
+      const response = await app.inject({
+        method: 'POST',
+        url: '/echo',
+        payload: { text: 'x'.repeat(2048) },
+      });
+
+      expect(response.statusCode).toBe(413);
+    });
+
+    it('should accept upload within limit', async () => {
+      const response = await app.inject(multipartUpload('hello'));
+
+      expect(response.statusCode).toBe(201);
+      expect(mockFileService.upload).toHaveBeenCalled();
+    });
+
+    it('should reject oversized upload with 413', async () => {
+      const response = await app.inject(multipartUpload('x'.repeat(2048)));
+
+      expect(response.statusCode).toBe(413);
+      expect(response.json()).toMatchObject({ code: 'file_too_large' });
+    });
+
+    it('should remove earlier files when a later one is too big', async () => {
+      const response = await app.inject(
+        multipartUpload('hello', 'x'.repeat(2048))
+      );
+      const storedId = await mockFileService.upload.mock.results[0].value;
+
+      expect(response.statusCode).toBe(413);
+      expect(response.json()).toMatchObject({ code: 'file_too_large' });
+      expect(mockFileService.deleteFile).toHaveBeenCalledWith(
+        storedId.toHexString()
+      );
+    });
+  });
+
+  describe('upload file count limit', () => {
+    beforeEach(async () => {
+      app = await createApp({ MAX_UPLOAD_FILES: '1' });
+    });
+
+    it('should reject extra files and remove stored ones', async () => {
+      const response = await app.inject(multipartUpload('hello', 'world'));
+      const storedId = await mockFileService.upload.mock.results[0].value;
+
+      expect(response.statusCode).toBe(413);
+      expect(response.json()).toMatchObject({ code: 'too_many_files' });
+      expect(mockFileService.deleteFile).toHaveBeenCalledWith(
+        storedId.toHexString()
+      );
+    });
+  });
+
+  describe('client ip', () => {
+    it.each([
+      {
+        name: 'ignores X-Forwarded-For when proxies are not trusted',
+        env: {},
+        headers: { 'x-forwarded-for': '203.0.113.7' },
+        expected: '127.0.0.1',
+      },
+      {
+        name: 'ignores X-Real-IP when proxies are not trusted',
+        env: {},
+        headers: { 'x-real-ip': '203.0.113.7' },
+        expected: '127.0.0.1',
+      },
+      {
+        name: 'uses socket address without headers',
+        env: { TRUST_PROXY_HEADERS: '1' },
+        headers: {},
+        expected: '127.0.0.1',
+      },
+      {
+        name: 'uses X-Forwarded-For from a trusted hop',
+        env: { TRUST_PROXY_HEADERS: '1' },
+        headers: { 'x-forwarded-for': '203.0.113.7' },
+        expected: '203.0.113.7',
+      },
+      {
+        name: 'ignores entries spoofed beyond the trusted hops',
+        env: { TRUST_PROXY_HEADERS: '1' },
+        headers: { 'x-forwarded-for': '198.51.100.1, 203.0.113.7' },
+        expected: '203.0.113.7',
+      },
+      {
+        name: 'uses X-Real-IP from a trusted hop',
+        env: { TRUST_PROXY_HEADERS: '1' },
+        headers: { 'x-real-ip': '203.0.113.8' },
+        expected: '203.0.113.8',
+      },
+      {
+        name: 'uses X-Forwarded-For from a listed proxy',
+        env: { TRUST_PROXY_HEADERS: '10.0.0.0/8' },
+        remoteAddress: '10.0.0.2',
+        headers: { 'x-forwarded-for': '203.0.113.9' },
+        expected: '203.0.113.9',
+      },
+      {
+        name: 'ignores X-Forwarded-For from an unlisted peer',
+        env: { TRUST_PROXY_HEADERS: '10.0.0.0/8' },
+        remoteAddress: '198.51.100.1',
+        headers: { 'x-forwarded-for': '203.0.113.9' },
+        expected: '198.51.100.1',
+      },
+    ])('should resolve ip: $name', async (testCase) => {
+      app = await createApp(testCase.env);
+
+      const response = await app.inject({
+        method: 'GET',
+        url: '/echo/ip',
+        headers: testCase.headers,
+        remoteAddress: testCase.remoteAddress,
+      });
+
+      expect(response.json()).toEqual({ ip: testCase.expected });
+    });
+
+    it('should parse trust proxy setting', () => {
+      expect(parseTrustProxy(undefined)).toBe(false);
+      expect(parseTrustProxy('false')).toBe(false);
+      expect(parseTrustProxy('2')).toBe(2);
+      expect(parseTrustProxy('10.0.0.1, 172.16.0.0/12')).toEqual([
+        '10.0.0.1',
+        '172.16.0.0/12',
+      ]);
+    });
+  });
+
+  describe('access log', () => {
+    let lines: Record<string, unknown>[];
+
+    const createLoggedApp = (env: NodeJS.ProcessEnv) => {
+      lines = [];
+      return createApp(env, {
+        level: 'info',
+        stream: { write: (line: string) => lines.push(JSON.parse(line)) },
+      });
+    };
+
+    it('should write one entry per request', async () => {
+      app = await createLoggedApp({});
+
+      await app.inject({ method: 'POST', url: '/echo?x=1', payload: {} });
+
+      expect(lines).toHaveLength(1);
+      expect(lines[0]).toMatchObject({
+        msg: 'request completed',
+        method: 'POST',
+        url: '/echo?x=1',
+        statusCode: 201,
+        reqId: expect.anything(),
+        responseTime: expect.any(Number),
+      });
+    });
+
+    it('should tag service logs with the request id', async () => {
+      app = await createLoggedApp({});
+
+      await app.inject({
+        method: 'POST',
+        url: '/echo/logged',
+        payload: { text: 'hello' },
+      });
+
+      const serviceLine = lines.find((line) => line.msg === 'Echoing body');
+      const accessLine = lines.find((line) => line.msg === 'request completed');
+      expect(serviceLine).toMatchObject({
+        context: 'EchoService',
+        reqId: expect.anything(),
+      });
+      expect(serviceLine.reqId).toBe(accessLine.reqId);
+    });
+
+    it('should skip configured paths', async () => {
+      app = await createLoggedApp({ ACCESS_LOG_SKIP_PATHS: '/healthz, /echo' });
+
+      await app.inject({ method: 'POST', url: '/echo?x=1', payload: {} });
+
+      expect(lines).toHaveLength(0);
+    });
+  });
+
+  it('should build defaults from empty env', () => {
+    expect(serverOptionsFromEnv({})).toEqual({
+      bodyLimit: 1048576,
+      maxUploadFiles: 10,
+      trustProxy: false,
+      logLevel: 'info',
+      accessLogSkipPaths: [],
+    });
+  });
+
+  it('should disable built-in request logging', () => {
+    expect(fastifyOptions(serverOptionsFromEnv({}))).toMatchObject({
+      logger: { level: 'info' },
+      disableRequestLogging: true,
+    });
+  });
+});
@@ -0,0 +1,132 @@
+import multipart from '@fastify/multipart';
+import { NestFastifyApplication } from '@nestjs/platform-fastify';
+import { FastifyServerOptions } from 'fastify';
+import { registerRequestContext } from './request-context';
+
+const DEFAULT_BODY_LIMIT = 1048576;
+const DEFAULT_MAX_UPLOAD_FILES = 10;
+
+/**
+ * HTTP server settings read from the environment.
+ */
+export interface ServerOptions {
+  /** Maximum size in bytes of a request body or a single uploaded file */
+  bodyLimit: number;
+  /**
+   * Maximum number of files in one multipart upload, so a request holds
+   * at most maxUploadFiles * bodyLimit bytes of files
+   */
+  maxUploadFiles: number;
+  /**
+   * Proxies trusted to set X-Forwarded-For / X-Real-IP for request.ip:
+   * false (none), a hop count, or a list of proxy IPs/CIDRs
+   */
+  trustProxy: false | number | string[];
+  /** Pino log level for the HTTP server logger */
+  logLevel: string;
+  /** Request paths (without query string) left out of the access log */
+  accessLogSkipPaths: string[];
+}
+
+/**
+ * Reads server settings from environment variables.
+ * @param env - Environment variables (usually process.env)
+ */
+export function serverOptionsFromEnv(env: NodeJS.ProcessEnv): ServerOptions {
+  return {
+    bodyLimit: Number(env.MAX_REQUEST_BODY_BYTES) || DEFAULT_BODY_LIMIT,
+    maxUploadFiles: Number(env.MAX_UPLOAD_FILES) || DEFAULT_MAX_UPLOAD_FILES,
+    trustProxy: parseTrustProxy(env.TRUST_PROXY_HEADERS),
+    logLevel: env.LOG_LEVEL || 'info',
+    accessLogSkipPaths: (env.ACCESS_LOG_SKIP_PATHS || '')
+      .split(',')
+      .map((path) => path.trim())
+      .filter(Boolean),
+  };
+}
+
+/**
+ * Parses TRUST_PROXY_HEADERS. Trusting every hop is deliberately not
+ * supported: clients could then pick their own request.ip by sending
+ * X-Forwarded-For, defeating per-IP rate limiting.
+ * @param value - "false", a hop count, or comma-separated proxy IPs/CIDRs
+ */
+export function parseTrustProxy(value?: string): false | number | string[] {
+  if (!value || value === 'false') {
+    return false;
+  }
+  if (/^\d+$/.test(value)) {
+    return Number(value);
+  }
+  return value
+    .split(',')
+    .map((entry) => entry.trim())
+    .filter(Boolean);
+}
+
+/**
+ * Builds options for the FastifyAdapter.
+ * Bodies above bodyLimit are rejected by Fastify with 413.
+ * @param options - Server settings
+ */
+export function fastifyOptions(options: ServerOptions): FastifyServerOptions {
+  return {
+    logger: { level: options.logLevel },
+    // Replaced by the single access log entry from configureServer
+    disableRequestLogging: true,
+    bodyLimit: options.bodyLimit,
+    trustProxy: options.trustProxy,
+  };
+}
+
+/**
+ * Registers Fastify plugins and hooks on the Nest application.
+ * Multipart bodies bypass Fastify's bodyLimit, so the same limit is
+ * applied per uploaded file and the number of files is capped. When
+ * proxies are trusted, X-Real-IP is used as a fallback for a missing
+ * X-Forwarded-For. Each response is written to the access log unless its
+ * path is listed in accessLogSkipPaths. Service logs made while handling a
+ * request carry its reqId (see RequestLogger).
+ * @param app - Nest application created with FastifyAdapter
+ * @param options - Server settings
+ */
+export async function configureServer(
+  app: NestFastifyApplication,
+  options: ServerOptions
+): Promise<void> {
+  await app.register(multipart, {
+    limits: { fileSize: options.bodyLimit, files: options.maxUploadFiles },
+  });
+
+  const fastify = app.getHttpAdapter().getInstance();
+  registerRequestContext(fastify);
+
+  if (options.trustProxy !== false) {
+    fastify.addHook('onRequest', async (request) => {
+      const headers = request.raw.headers;
+      const realIp = headers['x-real-ip'];
+      // request.ip is resolved from X-Forwarded-For with the same trust
+      // rules, so X-Real-IP from an untrusted peer is still ignored
+      if (!headers['x-forwarded-for'] && typeof realIp === 'string') {
+        headers['x-forwarded-for'] = realIp;
+      }
+    });
+  }
+
+  const skipPaths = new Set(options.accessLogSkipPaths);
+  fastify.addHook('onResponse', async (request, reply) => {
+    if (skipPaths.has(request.url.split('?')[0])) {
+      return;
+    }
+    // request.log is already bound to the request's reqId
+    request.log.info(
+      {
+        method: request.method,
+        url: request.url,
+        statusCode: reply.statusCode,
+        responseTime: reply.elapsedTime,
+      },
+      'request completed'
+    );
+  });
+}
@@ -0,0 +1,32 @@
+import { verifyStartup } from './startup';
+
+describe('verifyStartup', () => {
+  // Nothing listens on port 1, so the connection is refused right away
+  const unreachableUri = 'mongodb://127.0.0.1:1/codeed';
+
+  it('should report unreachable MongoDB', async () => {
+    await expect(
+      verifyStartup({ MONGO_URI: unreachableUri }, 200)
+    ).rejects.toThrow(
+      /^Startup checks failed: MongoDB is unreachable: .*ECONNREFUSED/
+    );
+  });
+
+  it('should report config and connectivity errors together', async () => {
+    const result = verifyStartup(
+      { MONGO_URI: unreachableUri, PORT: 'abc' },
+      200
+    );
+
+    await expect(result).rejects.toThrow(
+      'Startup checks failed: PORT must be a positive integer; ' +
+        'MongoDB is unreachable'
+    );
+  });
+
+  it('should not connect with an invalid MONGO_URI', async () => {
+    await expect(verifyStartup({}, 200)).rejects.toThrow(
+      new Error('Startup checks failed: MONGO_URI is required')
+    );
+  });
+});
@@ -0,0 +1,57 @@
+import { MongoClient } from 'mongodb';
+import { collectConfigErrors } from './config.validation';
+
+const DEFAULT_CONNECT_TIMEOUT_MS = 5000;
+
+/**
+ * Verifies configuration and MongoDB reachability before the app starts.
+ * Config and connectivity problems are reported together in one error,
+ * so a broken deployment can be fixed in a single pass.
+ * @param config - Raw environment variables
+ * @param timeoutMs - How long to wait for a MongoDB server
+ * @throws Error listing every problem found
+ */
+export async function verifyStartup(
+  config: Record<string, unknown>,
+  timeoutMs = DEFAULT_CONNECT_TIMEOUT_MS
+): Promise<void> {
+  const errors = collectConfigErrors(config);
+
+  // A malformed URI is already reported, connecting would only repeat it
+  if (!errors.some((error) => error.startsWith('MONGO_URI'))) {
+    const connectionError = await checkMongoConnection(
+      String(config.MONGO_URI),
+      timeoutMs
+    );
+    if (connectionError) {
+      errors.push(connectionError);
+    }
+  }
+
+  if (errors.length > 0) {
+    throw new Error(`Startup checks failed: ${errors.join('; ')}`);
+  }
+}
+
+/**
+ * Connects to MongoDB once and closes the connection again.
+ * The URI is left out of the message as it may contain credentials.
+ * @param uri - MongoDB connection string
+ * @param timeoutMs - Server selection timeout
+ * @returns Error message, or undefined when the server is reachable
+ */
+async function checkMongoConnection(
+  uri: string,
+  timeoutMs: number
+): Promise<string | undefined> {
+  let client: MongoClient | undefined;
+  try {
+    client = new MongoClient(uri, { serverSelectionTimeoutMS: timeoutMs });
+    await client.connect();
+    return undefined;
+  } catch (error) {
+    return `MongoDB is unreachable: ${error.message}`;
+  } finally {
+    await client?.close();
+  }
+}
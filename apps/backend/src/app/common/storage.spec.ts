@@ -0,0 +1,47 @@
+import {
+  MongoNetworkError,
+  MongoNetworkTimeoutError,
+  MongoNotConnectedError,
+  MongoRuntimeError,
+  MongoServerSelectionError,
+  MongoTopologyClosedError,
+  TopologyDescription,
+} from 'mongodb';
+import {
+  isStorageUnavailable,
+  StorageUnavailableError,
+  toStorageError,
+} from './storage';
+
+describe('storage errors', () => {
+  it.each([
+    new MongoNetworkError('connection reset'),
+    new MongoNetworkTimeoutError('timed out'),
+    new MongoServerSelectionError(
+      'Server selection timed out after 30000 ms',
+      {} as TopologyDescription
+    ),
+    new MongoTopologyClosedError(),
+    new MongoNotConnectedError('client not connected'),
+  ])('should wrap %p into StorageUnavailableError', (error) => {
+    expect(isStorageUnavailable(error)).toBe(true);
+
+    const wrapped = toStorageError(error);
+
+    expect(wrapped).toBeInstanceOf(StorageUnavailableError);
+    expect((wrapped as StorageUnavailableError).reason).toBe(error);
+  });
+
+  it('should pass other errors through', () => {
+    const error = new MongoRuntimeError('File not found');
+
+    expect(isStorageUnavailable(error)).toBe(false);
+    expect(toStorageError(error)).toBe(error);
+  });
+
+  it('should not wrap StorageUnavailableError twice', () => {
+    const error = new StorageUnavailableError();
+
+    expect(toStorageError(error)).toBe(error);
+  });
+});
@@ -0,0 +1,47 @@
+import {
+  MongoNetworkError,
+  MongoNotConnectedError,
+  MongoServerSelectionError,
+  MongoTopologyClosedError,
+} from 'mongodb';
+import { DomainError, ErrorCode } from './domain-error';
+
+/**
+ * StorageUnavailableError is thrown when MongoDB cannot be reached
+ * (network failure, timeout, no suitable server, or closed client).
+ */
+@ErrorCode('storage_unavailable')
+export class StorageUnavailableError extends DomainError {
+  constructor(readonly reason?: unknown) {
+    super('Storage is unavailable');
+    this.name = StorageUnavailableError.name;
+  }
+}
+
+/**
+ * Checks whether an error means MongoDB is unreachable rather than
+ * the operation itself being invalid.
+ * @param error - Error raised by the MongoDB driver
+ */
+export function isStorageUnavailable(error: unknown): boolean {
+  return (
+    error instanceof MongoNetworkError ||
+    error instanceof MongoServerSelectionError ||
+    error instanceof MongoTopologyClosedError ||
+    error instanceof MongoNotConnectedError
+  );
+}
+
+/**
+ * Wraps connectivity errors into StorageUnavailableError and passes
+ * any other error through unchanged.
+ * @param error - Error raised by the MongoDB driver
+ */
+export function toStorageError(error: unknown): unknown {
+  if (error instanceof StorageUnavailableError) {
+    return error;
+  }
+  return isStorageUnavailable(error)
+    ? new StorageUnavailableError(error)
+    : error;
+}
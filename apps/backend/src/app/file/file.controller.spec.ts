@@ -3,7 +3,17 @@ import { FileController } from './file.controller';
 import { FileService } from './file.service';
 import { FastifyReply, FastifyRequest } from 'fastify';
 import { Readable, Writable } from 'stream';
-import { ObjectId } from 'mongodb';
+import {
+  MongoRuntimeError,
+  MongoServerError,
+  MongoServerSelectionError,
+  ObjectId,
+  TopologyDescription,
+} from 'mongodb';
+import { once } from 'events';
+import { HttpException, HttpStatus } from '@nestjs/common';
+import { InvalidObjectIdError } from '../common/object-id';
+import { StorageUnavailableError } from '../common/storage';
 
 describe('FileController', () => {
   let controller: FileController;
@@ -55,6 +65,34 @@ describe('FileController', () => {
     expect(result).toEqual([{ filename: 'test.txt', fileId }]);
   });
 
+  it('should remove stored files when a later file is too large', async () => {
+    const storedId = new ObjectId();
+    const truncatedId = new ObjectId();
+    const req = {
+      parts: async function* () {
+        yield { file: Readable.from(['small']), filename: 'small.txt' };
+        yield {
+          file: Object.assign(Readable.from(['cut off']), { truncated: true }),
+          filename: 'large.txt',
+        };
+      },
+    } as unknown as FastifyRequest;
+
+    mockFileService.upload
+      .mockResolvedValueOnce(storedId)
+      .mockResolvedValueOnce(truncatedId);
+
+    await expect(() => controller.upload(req)).rejects.toMatchObject({
+      response: { code: 'file_too_large' },
+    });
+    expect(mockFileService.deleteFile).toHaveBeenCalledWith(
+      truncatedId.toHexString()
+    );
+    expect(mockFileService.deleteFile).toHaveBeenCalledWith(
+      storedId.toHexString()
+    );
+  });
+
   it('should return empty array if no file uploaded', async () => {
     const req = {
       // eslint-disable-next-line require-yield
@@ -73,11 +111,7 @@ describe('FileController', () => {
 
     const res = {
       header: jest.fn(),
-      raw: new Writable({
-        write(chunk, _encoding, callback) {
-          callback();
-        },
-      }),
+      send: jest.fn(),
     } as unknown as FastifyReply;
 
     await controller.getFile('507f191e810c19729de860ea', res);
@@ -86,11 +120,99 @@ describe('FileController', () => {
       'Content-Type',
       'application/octet-stream'
     );
+    expect(res.send).toHaveBeenCalledWith(expect.any(Readable));
+  });
+
+  it.each([
+    {
+      name: 'storage is unavailable',
+      streamError: new MongoServerSelectionError(
+        'connection refused',
+        {} as TopologyDescription
+      ),
+      status: HttpStatus.SERVICE_UNAVAILABLE,
+      code: 'storage_unavailable',
+    },
+    {
+      name: 'file is missing',
+      streamError: new MongoRuntimeError(
+        'FileNotFound: file 507f191e810c19729de860ea was not found'
+      ),
+      status: HttpStatus.NOT_FOUND,
+      code: 'file_not_found',
+    },
+  ])('should fail stream if $name', async ({ streamError, status, code }) => {
+    mockFileService.streamFile.mockReturnValue(
+      new Readable({
+        read() {
+          this.destroy(streamError);
+        },
+      })
+    );
+
+    const res = {
+      header: jest.fn(),
+      send: jest.fn(),
+    } as unknown as FastifyReply;
+
+    await controller.getFile('507f191e810c19729de860ea', res);
+    const body = (res.send as jest.Mock).mock.calls[0][0] as Readable;
+    const [error] = await once(body, 'error');
+
+    expect(error).toBeInstanceOf(HttpException);
+    expect((error as HttpException).getStatus()).toBe(status);
+    expect((error as HttpException).getResponse()).toMatchObject({ code });
+  });
+
+  it('should pass unexpected stream errors through', async () => {
+    const streamError = new MongoServerError({
+      message: 'not authorized on codeed to execute command',
+      code: 13,
+    });
+    mockFileService.streamFile.mockReturnValue(
+      new Readable({
+        read() {
+          this.destroy(streamError);
+        },
+      })
+    );
+
+    const res = {
+      header: jest.fn(),
+      send: jest.fn(),
+    } as unknown as FastifyReply;
+
+    await controller.getFile('507f191e810c19729de860ea', res);
+    const body = (res.send as jest.Mock).mock.calls[0][0] as Readable;
+    const [error] = await once(body, 'error');
+
+    expect(error).toBe(streamError);
   });
 
-  it('should return 404 if stream fails', async () => {
+  it('should close file stream when response body is destroyed', async () => {
+    const fileStream = new Readable({
+      read() {
+        // Never ends, like a download the client abandons midway
+      },
+    });
+    mockFileService.streamFile.mockReturnValue(fileStream);
+
+    const res = {
+      header: jest.fn(),
+      send: jest.fn(),
+    } as unknown as FastifyReply;
+
+    await controller.getFile('507f191e810c19729de860ea', res);
+    const body = (res.send as jest.Mock).mock.calls[0][0] as Readable;
+    body.destroy();
+    await once(fileStream, 'close');
+
+    expect(fileStream.destroyed).toBe(true);
+  });
+
+  it('should rethrow unexpected errors when opening stream', async () => {
     mockFileService.streamFile.mockImplementation(() => {
-      throw new Error('not found');
+      throw new Error('unexpected');
     });
 
     const res = {
@@ -103,7 +225,7 @@ describe('FileController', () => {
     } as unknown as FastifyReply;
 
     await expect(() => controller.getFile('bad_id', res)).rejects.toThrow(
-      'File not found'
+      'unexpected'
     );
   });
 
@@ -113,10 +235,82 @@ describe('FileController', () => {
     expect(mockFileService.deleteFile).toHaveBeenCalledWith(id);
   });
 
-  it('should return 404 if delete fails', async () => {
-    mockFileService.deleteFile.mockRejectedValue(new Error('not found'));
-    await expect(() => controller.deleteFile('bad_id')).rejects.toThrow(
-      'File not found'
+  it('should return 404 if file to delete is missing', async () => {
+    mockFileService.deleteFile.mockRejectedValue(
+      new MongoRuntimeError('File not found for id 507f191e810c19729de860ea')
     );
+    await expect(() =>
+      controller.deleteFile('507f191e810c19729de860ea')
+    ).rejects.toMatchObject({
+      response: {
+        statusCode: HttpStatus.NOT_FOUND,
+        error: 'Not Found',
+        message: 'File not found',
+        code: 'file_not_found',
+      },
+    });
+  });
+
+  it('should rethrow unexpected delete errors', async () => {
+    const deleteError = new MongoServerError({
+      message: 'not authorized on codeed to execute command',
+      code: 13,
+    });
+    mockFileService.deleteFile.mockRejectedValue(deleteError);
+
+    await expect(() =>
+      controller.deleteFile('507f191e810c19729de860ea')
+    ).rejects.toBe(deleteError);
+  });
+
+  it('should return 400 if stream id is invalid', async () => {
+    mockFileService.streamFile.mockImplementation(() => {
+      throw new InvalidObjectIdError('bad_id');
+    });
+
+    const res = {
+      header: jest.fn(),
+      raw: new Writable({
+        write(_chunk, _encoding, callback) {
+          callback();
+        },
+      }),
+    } as unknown as FastifyReply;
+
+    await expect(() => controller.getFile('bad_id', res)).rejects.toThrow(
+      'Invalid file ID'
+    );
+  });
+
+  it('should return 400 if delete id is invalid', async () => {
+    mockFileService.deleteFile.mockRejectedValue(
+      new InvalidObjectIdError('bad_id')
+    );
+    await expect(() => controller.deleteFile('bad_id')).rejects.toMatchObject({
+      response: { message: 'Invalid file ID', code: 'invalid_object_id' },
+    });
+  });
+
+  it('should return 503 if storage is unavailable on upload', async () => {
+    const req = {
+      parts: async function* () {
+        yield { file: Readable.from(['file content']), filename: 'test.txt' };
+      },
+    } as unknown as FastifyRequest;
+
+    mockFileService.upload.mockRejectedValue(new StorageUnavailableError());
+
+    await expect(() => controller.upload(req)).rejects.toThrow(
+      'Storage unavailable'
+    );
+  });
+
+  it('should return 503 if storage is unavailable on delete', async () => {
+    mockFileService.deleteFile.mockRejectedValue(new StorageUnavailableError());
+    await expect(() =>
+      controller.deleteFile('507f191e810c19729de860ea')
+    ).rejects.toMatchObject({
+      response: { message: 'Storage unavailable', code: 'storage_unavailable' },
+    });
   });
 });
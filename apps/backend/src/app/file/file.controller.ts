@@ -3,7 +3,6 @@ import {
   Delete,
   Get,
   HttpCode,
-  HttpException,
   HttpStatus,
   Logger,
   Param,
@@ -12,8 +11,16 @@ import {
   Res,
 } from '@nestjs/common';
 import { FastifyReply, FastifyRequest } from 'fastify';
+import { PassThrough, pipeline } from 'stream';
 import { FileService } from './file.service';
+import {
+  FileNotFoundError,
+  FileTooLargeError,
+  isGridFSFileNotFound,
+  TooManyFilesError,
+} from './file.errors';
 import { MultipartFile } from '@fastify/multipart';
+import { ObjectId } from 'mongodb';
 import {
   ApiBadRequestResponse,
   ApiBody,
@@ -24,8 +31,13 @@ import {
   ApiOkResponse,
   ApiOperation,
   ApiParam,
+  ApiPayloadTooLargeResponse,
+  ApiServiceUnavailableResponse,
   ApiTags,
 } from '@nestjs/swagger';
+import { toHttpException } from '../common/domain-error';
+import { InvalidObjectIdError } from '../common/object-id';
+import { StorageUnavailableError, toStorageError } from '../common/storage';
 
 @ApiTags('Files')
 @Controller('files')
@@ -52,22 +64,64 @@ export class FileController {
   })
   @ApiCreatedResponse({ description: 'File(s) uploaded successfully' })
   @ApiBadRequestResponse({ description: 'Invalid file format or request' })
+  @ApiPayloadTooLargeResponse({
+    description: 'File exceeds size limit or too many files',
+  })
+  @ApiServiceUnavailableResponse({ description: 'Storage is unavailable' })
   async upload(@Req() req: FastifyRequest) {
     const parts = req.parts();
-    const result = [];
+    const result: { filename: string; fileId: ObjectId }[] = [];
 
-    for await (const part of parts) {
-      if ((part as MultipartFile).file) {
-        const filePart = part as MultipartFile;
-        this.logger.log(`Uploading file: ${filePart.filename}`);
+    try {
+      for await (const part of parts) {
+        if ((part as MultipartFile).file) {
+          const filePart = part as MultipartFile;
+          this.logger.log(`Uploading file: ${filePart.filename}`);
 
-        const fileId = await this.fileService.upload(
-          filePart.filename,
-          filePart.file
+          const fileId = await this.fileService.upload(
+            filePart.filename,
+            filePart.file
+          );
+          // busboy ends the stream early once the size limit is hit
+          if (filePart.file.truncated) {
+            await this.fileService.deleteFile(fileId.toHexString());
+            throw new FileTooLargeError();
+          }
+          result.push({ filename: filePart.filename, fileId });
+        }
+      }
+    } catch (error) {
+      // The client gets no IDs on failure, so files stored so far would be
+      // orphaned in GridFS
+      await this.removeUploaded(result);
+      if (
+        error instanceof FileTooLargeError ||
+        error?.code === 'FST_REQ_FILE_TOO_LARGE'
+      ) {
+        this.logger.warn('Uploaded file exceeds size limit');
+        throw toHttpException(
+          new FileTooLargeError(),
+          'File too large',
+          HttpStatus.PAYLOAD_TOO_LARGE
         );
-
-        result.push({ filename: filePart.filename, fileId });
       }
+      if (error?.code === 'FST_FILES_LIMIT') {
+        this.logger.warn('Upload exceeds file count limit');
+        throw toHttpException(
+          new TooManyFilesError(),
+          'Too many files',
+          HttpStatus.PAYLOAD_TOO_LARGE
+        );
+      }
+      if (error instanceof StorageUnavailableError) {
+        this.logger.error('Storage is unavailable');
+        throw toHttpException(
+          error,
+          'Storage unavailable',
+          HttpStatus.SERVICE_UNAVAILABLE
+        );
+      }
+      throw error;
     }
 
     this.logger.log(`Uploaded ${result.length} file(s)`);
@@ -78,17 +132,37 @@ export class FileController {
   @ApiOperation({ summary: 'Download a file by ID' })
   @ApiParam({ name: 'id', type: 'string', description: 'GridFS file ID' })
   @ApiOkResponse({ description: 'File streamed successfully' })
+  @ApiBadRequestResponse({ description: 'Invalid file ID' })
   @ApiNotFoundResponse({ description: 'File not found' })
+  @ApiServiceUnavailableResponse({ description: 'Storage is unavailable' })
   async getFile(@Param('id') id: string, @Res() res: FastifyReply) {
     this.logger.log(`Streaming file with ID: ${id}`);
+    let stream: NodeJS.ReadableStream;
     try {
-      const stream = this.fileService.streamFile(id);
-      res.header('Content-Type', 'application/octet-stream');
-      stream.pipe(res.raw);
+      stream = this.fileService.streamFile(id);
     } catch (error) {
-      this.logger.error(`Failed to stream file: ${error.message}`);
-      throw new HttpException('File not found', HttpStatus.NOT_FOUND);
+      throw this.toFileError(id, error);
     }
+
+    // GridFS reads lazily, so a missing file or storage outage only shows up
+    // as a stream error. pipeline destroys body with that error, which is
+    // mapped here so Fastify hands an HttpException to Nest's exception
+    // filter while the headers are still unsent. When Fastify destroys body
+    // after a client abort, pipeline closes the GridFS stream and its cursor.
+    const body = new PassThrough({
+      destroy: (error, callback) => {
+        const mapped = error && this.toFileError(id, toStorageError(error));
+        callback(mapped as Error);
+      },
+    });
+    pipeline(stream, body, (error) => {
+      if (error) {
+        this.logger.warn(`Streaming file ${id} stopped: ${error.message}`);
+      }
+    });
+
+    res.header('Content-Type', 'application/octet-stream');
+    return res.send(body);
   }
 
   @Delete(':id')
@@ -96,14 +170,59 @@ export class FileController {
   @ApiOperation({ summary: 'Delete a file by ID' })
   @ApiParam({ name: 'id', type: 'string', description: 'GridFS file ID' })
   @ApiNoContentResponse({ description: 'File deleted successfully' })
+  @ApiBadRequestResponse({ description: 'Invalid file ID' })
   @ApiNotFoundResponse({ description: 'File not found' })
+  @ApiServiceUnavailableResponse({ description: 'Storage is unavailable' })
   async deleteFile(@Param('id') id: string) {
     this.logger.log(`Deleting file with ID: ${id}`);
     try {
       await this.fileService.deleteFile(id);
-    } catch {
+    } catch (error) {
+      throw this.toFileError(id, error);
+    }
+  }
+
+  /**
+   * Deletes files stored by a failed upload. Failures are only logged so
+   * the original upload error reaches the client.
+   */
+  private async removeUploaded(files: { fileId: ObjectId }[]): Promise<void> {
+    const results = await Promise.allSettled(
+      files.map(({ fileId }) =>
+        this.fileService.deleteFile(fileId.toHexString())
+      )
+    );
+    const failed = results.filter((r) => r.status === 'rejected').length;
+    if (failed > 0) {
+      this.logger.error(`Failed to remove ${failed} orphaned upload(s)`);
+    }
+  }
+
+  /**
+   * Maps known file errors to HttpExceptions. Anything else is returned
+   * unchanged so Nest responds with 500 instead of hiding the fault.
+   */
+  private toFileError(id: string, error: unknown): unknown {
+    if (error instanceof InvalidObjectIdError) {
+      this.logger.warn(`Invalid file ID: ${id}`);
+      return toHttpException(error, 'Invalid file ID', HttpStatus.BAD_REQUEST);
+    }
+    if (error instanceof StorageUnavailableError) {
+      this.logger.error('Storage is unavailable');
+      return toHttpException(
+        error,
+        'Storage unavailable',
+        HttpStatus.SERVICE_UNAVAILABLE
+      );
+    }
+    if (isGridFSFileNotFound(error)) {
       this.logger.warn(`File not found: ${id}`);
-      throw new HttpException('File not found', HttpStatus.NOT_FOUND);
+      return toHttpException(
+        new FileNotFoundError(id),
+        'File not found',
+        HttpStatus.NOT_FOUND
+      );
     }
+    return error;
   }
 }
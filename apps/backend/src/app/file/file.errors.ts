@@ -0,0 +1,50 @@
+import { MongoRuntimeError } from 'mongodb';
+import { DomainError, ErrorCode } from '../common/domain-error';
+
+// GridFS reports a missing file as "FileNotFound: ..." when downloading
+// and as "File not found for id ..." when deleting
+const GRIDFS_NOT_FOUND_PATTERN = /^(FileNotFound|File not found)\b/;
+
+/**
+ * FileTooLargeError is thrown when an uploaded file exceeds the size limit.
+ */
+@ErrorCode('file_too_large')
+export class FileTooLargeError extends DomainError {
+  constructor() {
+    super('File exceeds the upload size limit');
+    this.name = FileTooLargeError.name;
+  }
+}
+
+/**
+ * TooManyFilesError is thrown when an upload has more files than allowed.
+ */
+@ErrorCode('too_many_files')
+export class TooManyFilesError extends DomainError {
+  constructor() {
+    super('Upload exceeds the file count limit');
+    this.name = TooManyFilesError.name;
+  }
+}
+
+/**
+ * FileNotFoundError is thrown when no stored file matches the given ID.
+ */
+@ErrorCode('file_not_found')
+export class FileNotFoundError extends DomainError {
+  constructor(readonly fileId: string) {
+    super(`File not found: ${fileId}`);
+    this.name = FileNotFoundError.name;
+  }
+}
+
+/**
+ * Checks whether a GridFS error means the requested file does not exist.
+ * @param error - Error raised by the MongoDB driver
+ */
+export function isGridFSFileNotFound(error: unknown): boolean {
+  return (
+    error instanceof MongoRuntimeError &&
+    GRIDFS_NOT_FOUND_PATTERN.test(error.message)
+  );
+}
@@ -2,8 +2,19 @@
 import { Test, TestingModule } from '@nestjs/testing';
 import { FileService } from './file.service';
 import { getConnectionToken } from '@nestjs/mongoose';
-import { GridFSBucket, ObjectId } from 'mongodb';
+import { ConfigService } from '@nestjs/config';
+import {
+  GridFSBucket,
+  MongoError,
+  MongoErrorLabel,
+  MongoNetworkError,
+  MongoRuntimeError,
+  MongoTopologyClosedError,
+  ObjectId,
+} from 'mongodb';
 import { Readable, Writable } from 'stream';
+import { InvalidObjectIdError } from '../common/object-id';
+import { StorageUnavailableError } from '../common/storage';
 
 describe('FileService', () => {
   let service: FileService;
@@ -17,13 +28,7 @@ describe('FileService', () => {
           callback();
         },
       }),
-      {
-        id: new ObjectId(),
-        on: jest.fn().mockImplementation(function (event, cb) {
-          if (event === 'finish') cb();
-          return this;
-        }),
-      }
+      { id: new ObjectId() }
     );
 
     const deleteMock = jest.fn().mockResolvedValue(undefined);
@@ -51,6 +56,13 @@ describe('FileService', () => {
           provide: getConnectionToken(),
           useValue: mockConnection,
         },
+        {
+          provide: ConfigService,
+          useValue: {
+            get: (key: string) =>
+              ({ WRITE_RETRY_ATTEMPTS: '3', WRITE_RETRY_BACKOFF_MS: '1' }[key]),
+          },
+        },
       ],
     }).compile();
 
@@ -81,4 +93,91 @@ describe('FileService', () => {
 
     expect(bucketMock.delete).toHaveBeenCalledWith(new ObjectId(id));
   });
+
+  it('should reject invalid file id', async () => {
+    expect(() => service.streamFile('bad_id')).toThrow(InvalidObjectIdError);
+    await expect(service.deleteFile('bad_id')).rejects.toThrow(
+      InvalidObjectIdError
+    );
+    expect(bucketMock.openDownloadStream).not.toHaveBeenCalled();
+    expect(bucketMock.delete).not.toHaveBeenCalled();
+  });
+
+  it('should wrap closed client error on delete', async () => {
+    (bucketMock.delete as jest.Mock).mockRejectedValue(
+      new MongoTopologyClosedError()
+    );
+
+    await expect(
+      service.deleteFile('507f191e810c19729de860ea')
+    ).rejects.toThrow(StorageUnavailableError);
+  });
+
+  it('should wrap storage error during upload', async () => {
+    (bucketMock.openUploadStream as jest.Mock).mockReturnValue(
+      new Writable({
+        write(_chunk, _encoding, callback) {
+          callback(new MongoNetworkError('connection reset'));
+        },
+      })
+    );
+
+    await expect(
+      service.upload('test.txt', Readable.from(['some content']))
+    ).rejects.toThrow(StorageUnavailableError);
+  });
+
+  it('should reject upload when source stream fails', async () => {
+    const source = new Readable({
+      read() {
+        this.destroy(new Error('client aborted'));
+      },
+    });
+
+    await expect(service.upload('test.txt', source)).rejects.toThrow(
+      'client aborted'
+    );
+  });
+
+  it('should retry delete on retryable error', async () => {
+    const stepdown = new MongoError('not primary');
+    stepdown.addErrorLabel(MongoErrorLabel.RetryableWriteError);
+    (bucketMock.delete as jest.Mock)
+      .mockRejectedValueOnce(stepdown)
+      .mockResolvedValueOnce(undefined);
+
+    await service.deleteFile('507f191e810c19729de860ea');
+
+    expect(bucketMock.delete).toHaveBeenCalledTimes(2);
+  });
+
+  it('should not retry delete of missing file', async () => {
+    (bucketMock.delete as jest.Mock).mockRejectedValue(
+      new MongoRuntimeError('File not found for id 507f191e810c19729de860ea')
+    );
+
+    await expect(
+      service.deleteFile('507f191e810c19729de860ea')
+    ).rejects.toThrow('File not found');
+    expect(bucketMock.delete).toHaveBeenCalledTimes(1);
+  });
+
+  it('should treat missing file on retry as deleted', async () => {
+    const id = '507f191e810c19729de860ea';
+    const lostAck = new MongoError('connection reset');
+    lostAck.addErrorLabel(MongoErrorLabel.RetryableWriteError);
+    (bucketMock.delete as jest.Mock)
+      .mockRejectedValueOnce(lostAck)
+      .mockRejectedValueOnce(
+        new MongoRuntimeError(`File not found for id ${id}`)
+      );
+    const deleteMany = jest.fn().mockResolvedValue({ deletedCount: 2 });
+    mockConnection.db.collection.mockReturnValue({ deleteMany });
+
+    await service.deleteFile(id);
+
+    expect(bucketMock.delete).toHaveBeenCalledTimes(2);
+    expect(mockConnection.db.collection).toHaveBeenCalledWith('fs.chunks');
+    expect(deleteMany).toHaveBeenCalledWith({ files_id: new ObjectId(id) });
+  });
 });
@@ -1,18 +1,36 @@
-import { Injectable, Logger } from '@nestjs/common';
+import { Injectable } from '@nestjs/common';
+import { ConfigService } from '@nestjs/config';
 import { InjectConnection } from '@nestjs/mongoose';
 import { Connection } from 'mongoose';
 import { GridFSBucket, ObjectId } from 'mongodb';
+import { AsyncResource } from 'async_hooks';
+import { pipeline } from 'stream';
+import { parseObjectId } from '../common/object-id';
+import { toStorageError } from '../common/storage';
+import { RequestLogger } from '../common/request-context';
+import { RetryOptions, withRetry } from '../common/retry';
+import { isGridFSFileNotFound } from './file.errors';
+
+const CHUNKS_COLLECTION = 'fs.chunks';
 
 /**
  * FileService handles upload, streaming, and deletion of files via MongoDB GridFS.
  */
 @Injectable()
 export class FileService {
-  private readonly logger = new Logger(FileService.name);
+  private readonly logger = new RequestLogger(FileService.name);
   private bucket: GridFSBucket;
+  private readonly retryOptions: RetryOptions;
 
-  constructor(@InjectConnection() private readonly connection: Connection) {
+  constructor(
+    @InjectConnection() private readonly connection: Connection,
+    configService: ConfigService
+  ) {
     this.bucket = new GridFSBucket(this.connection.db);
+    this.retryOptions = {
+      attempts: Number(configService.get('WRITE_RETRY_ATTEMPTS')) || 3,
+      backoffMs: Number(configService.get('WRITE_RETRY_BACKOFF_MS')) || 100,
+    };
     this.logger.log('GridFS bucket initialized');
   }
 
@@ -21,6 +39,7 @@ export class FileService {
    * @param filename - Original file name
    * @param stream - Readable stream from the file
    * @returns ObjectId of the uploaded file
+   * @throws StorageUnavailableError if MongoDB is unreachable
    */
   upload(filename: string, stream: NodeJS.ReadableStream): Promise<ObjectId> {
     this.logger.log(`Starting upload: ${filename}`);
@@ -28,38 +47,66 @@ export class FileService {
     return new Promise<ObjectId>((resolve, reject) => {
       const uploadStream = this.bucket.openUploadStream(filename);
 
-      stream
-        .pipe(uploadStream)
-        .on('error', (err) => {
+      // Stream events fire from socket callbacks, so the callback is bound
+      // to keep the request context for logging
+      const done = AsyncResource.bind((err?: Error | null) => {
+        if (err) {
           this.logger.error(`Upload failed: ${filename}`, err);
-          reject(err);
-        })
-        .on('finish', () => {
-          this.logger.log(
-            `Upload finished: ${filename} (id: ${uploadStream.id})`
-          );
-          resolve(uploadStream.id);
-        });
+          reject(toStorageError(err));
+          return;
+        }
+        this.logger.log(
+          `Upload finished: ${filename} (id: ${uploadStream.id})`
+        );
+        resolve(uploadStream.id);
+      });
+      // pipeline (unlike pipe) also propagates errors from the source stream
+      pipeline(stream, uploadStream, done);
     });
   }
 
   /**
    * Streams a file from GridFS by its ID.
    * @param fileId - ObjectId as string
-   * @returns Readable stream of the file
+   * @returns Readable stream of the file; a missing file or unreachable
+   * storage is reported as an 'error' event on the stream
+   * @throws InvalidObjectIdError if fileId is not a valid ObjectId
    */
   streamFile(fileId: string): NodeJS.ReadableStream {
     this.logger.log(`Streaming file with ID: ${fileId}`);
-    return this.bucket.openDownloadStream(new ObjectId(fileId));
+    return this.bucket.openDownloadStream(parseObjectId(fileId));
   }
 
   /**
-   * Deletes a file from GridFS by its ID.
+   * Deletes a file from GridFS by its ID, retrying transient failures.
+   * GridFS removes the file document before its chunks, so a retry may
+   * find the document already gone; the delete then counts as done once
+   * leftover chunks are removed.
    * @param fileId - ObjectId as string
+   * @throws InvalidObjectIdError if fileId is not a valid ObjectId
+   * @throws StorageUnavailableError if MongoDB is unreachable
    */
   async deleteFile(fileId: string): Promise<void> {
     this.logger.log(`Deleting file with ID: ${fileId}`);
-    await this.bucket.delete(new ObjectId(fileId));
+    const id = parseObjectId(fileId);
+    let attempt = 0;
+    try {
+      await withRetry(async () => {
+        attempt++;
+        try {
+          await this.bucket.delete(id);
+        } catch (error) {
+          if (attempt === 1 || !isGridFSFileNotFound(error)) {
+            throw error;
+          }
+          await this.connection.db
+            .collection(CHUNKS_COLLECTION)
+            .deleteMany({ files_id: id });
+        }
+      }, this.retryOptions);
+    } catch (error) {
+      throw toStorageError(error);
+    }
     this.logger.log(`File deleted: ${fileId}`);
   }
 }
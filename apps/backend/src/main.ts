@@ -5,20 +5,30 @@
 
 import { Logger, ValidationPipe } from '@nestjs/common';
 import { NestFactory } from '@nestjs/core';
+import { ConfigModule } from '@nestjs/config';
 import { AppModule } from './app/app.module';
 import {
   FastifyAdapter,
   NestFastifyApplication,
 } from '@nestjs/platform-fastify';
-import multipart from '@fastify/multipart';
 import { DocumentBuilder, SwaggerModule } from '@nestjs/swagger';
+import {
+  configureServer,
+  fastifyOptions,
+  serverOptionsFromEnv,
+} from './app/common/server.config';
+import { verifyStartup } from './app/common/startup';
 
 async function bootstrap() {
+  // .env is loaded by ConfigModule.forRoot when AppModule is imported
+  await ConfigModule.envVariablesLoaded;
+  await verifyStartup(process.env);
+  const serverOptions = serverOptionsFromEnv(process.env);
   const app = await NestFactory.create<NestFastifyApplication>(
     AppModule,
-    new FastifyAdapter({ logger: true })
+    new FastifyAdapter(fastifyOptions(serverOptions))
   );
-  await app.register(multipart);
+  await configureServer(app, serverOptions);
   const globalPrefix = 'api';
   app.setGlobalPrefix(globalPrefix);
   app.useGlobalPipes(